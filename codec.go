@@ -0,0 +1,84 @@
+package sqlsess
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+func init() {
+	// time.Time is common enough as a session value (e.g. a last-seen
+	// timestamp) that it's worth registering up front; every other
+	// struct type still needs its own gob.Register call.
+	gob.Register(time.Time{})
+}
+
+// Codec encodes and decodes arbitrary session values to and from bytes so
+// that they can round-trip through a SQL column without being forced
+// through a string representation. Decode's v is always a pointer to the
+// destination, e.g. the *interface{} that Store.New decodes each value
+// into.
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+// gobValue wraps an encoded value so gob always sees the same concrete
+// type (gobValue) on the wire. Without this, encoding v directly makes
+// v's own concrete type the top-level type, which gob then refuses to
+// decode into a *interface{} destination ("local interface type ...
+// can only be decoded from remote interface type").
+type gobValue struct {
+	V interface{}
+}
+
+// GobCodec encodes values with encoding/gob. It is the default codec used
+// by Store, and can round-trip basic types (int, string, etc.) and
+// time.Time without any extra setup, since gob registers those
+// automatically (time.Time is registered by this package's init).
+//
+// Any other custom struct type must be registered with gob.Register
+// before it can be encoded or decoded as a session value, since gob
+// needs to know the concrete type to reconstruct behind the interface{}
+// value column.
+type GobCodec struct{}
+
+func (GobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&gobValue{V: v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte, v interface{}) error {
+	var w gobValue
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&w); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("sqlsess: Decode destination must be a pointer, got %T", v)
+	}
+	if w.V != nil {
+		rv.Elem().Set(reflect.ValueOf(w.V))
+	}
+	return nil
+}
+
+// JSONCodec encodes values with encoding/json. It is useful when session
+// values need to be inspected or edited outside of Go, at the cost of
+// losing concrete type information on decode (see json.Unmarshal's usual
+// caveats for decoding into interface{}).
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
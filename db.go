@@ -1,8 +1,11 @@
 package sqlsess
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/securecookie"
@@ -12,77 +15,104 @@ import (
 )
 
 var (
-	SessionLastUpdated = "__sess_last_updated"
-	CookieIdName       = "sess_sessionid"
-	SqlTableName       = "sess_session"
-	SqlCreateSession   = `
-	CREATE TABLE ` + SqlTableName + ` IF NOT EXISTS (
-		id BYTEA NOT NULL,
-		name VARCHAR (255) NOT NULL,
-		key TEXT NOT NULL,
-		value TEXT NOT NULL,
-		PRIMARY KEY (id, name, key)
-	)
-	`
+	CookieIdName = "sess_sessionid"
+	SqlTableName = "sess_session"
 )
 
 type Store struct {
 	*sql.DB
-	hashKey, blockKey []byte
+	codecs  []securecookie.Codec
+	dialect Dialect
+
+	// Codec encodes and decodes session values before they're written to
+	// or read from the value column. It defaults to GobCodec, which can
+	// round-trip arbitrary concrete Go types once they're registered with
+	// gob (see GobCodec). Set it before the store is
+	// used if a different encoding (e.g. JSONCodec) is required.
+	Codec Codec
+
+	// Options seeds sessions.Session.Options for every session returned
+	// by New. Callers can still override Options on a per-session basis
+	// before calling Save. If nil, new sessions get Options with Path "/"
+	// and HttpOnly set, and every other field left at its zero value.
+	Options *sessions.Options
 }
 
-func Open(db *sql.DB) (*Store, error) {
-	if _, err := db.Exec(SqlCreateSession); err != nil {
-		return nil, err
+// Open creates a Store backed by db, using dialect to generate
+// dialect-appropriate DDL and parameter placeholders. The session table is
+// created if it doesn't already exist.
+func Open(db *sql.DB, dialect Dialect) (*Store, error) {
+	for _, stmt := range dialect.CreateTableSQL() {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
 	}
 
 	s := &Store{
-		DB:       db,
-		hashKey:  securecookie.GenerateRandomKey(64),
-		blockKey: securecookie.GenerateRandomKey(32),
+		DB: db,
+		codecs: securecookie.CodecsFromPairs(
+			securecookie.GenerateRandomKey(64),
+			securecookie.GenerateRandomKey(32),
+		),
+		dialect: dialect,
+		Codec:   GobCodec{},
 	}
 	return s, nil
 }
 
+// OpenPostgres is Open with PostgresDialect, kept for callers that used
+// Open before it took a Dialect.
+func OpenPostgres(db *sql.DB) (*Store, error) {
+	return Open(db, PostgresDialect{})
+}
+
+// ph returns the n'th (1-indexed) parameter placeholder for the store's
+// dialect.
+func (s *Store) ph(n int) string {
+	return s.dialect.Placeholder(n)
+}
+
+// Clean deletes every session row whose updated_at is older than
+// inactive, in a single statement pushed down to the database.
 func (s *Store) Clean(inactive time.Duration) error {
 	locker.Lock("clean")
 	defer locker.Unlock("clean")
 
-	rows, err := s.Query(`
-		SELECT id, value
-		FROM ` + SqlTableName + `
-		WHERE key = $1
-	`, SessionLastUpdated)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
 	cutoff := time.Now().UTC().Add(-inactive)
-	for rows.Next() {
-		var id []byte
-		var last string
-		if err := rows.Scan(&id, &last); err != nil {
-			return err
-		}
-		lastUp, err := time.Parse(time.RFC3339Nano, last)
-		if err != nil {
-			return err
-		}
-		if lastUp.Before(cutoff) {
-			_, err = s.Exec(
-				"DELETE FROM " + SqlTableName + " WHERE id = $1", id)
-			if err != nil {
-				return err
+	_, err := s.Exec(
+		"DELETE FROM "+SqlTableName+" WHERE updated_at < "+s.ph(1), cutoff)
+	return err
+}
+
+// StartGC runs Clean on a ticker firing every interval, deleting sessions
+// that have been inactive for longer than inactive, until ctx is
+// cancelled. It is meant to be run in its own goroutine, e.g.:
+//
+//	go store.StartGC(ctx, 10*time.Minute, 24*time.Hour, func(err error) {
+//		log.Println("sqlsess: GC:", err)
+//	})
+//
+// onErr is called with every error Clean returns, so a GC loop that's
+// been failing (bad connection, schema drift) doesn't fail silently. It
+// may be nil to ignore errors, e.g. for tests that don't care.
+func (s *Store) StartGC(ctx context.Context, interval, inactive time.Duration, onErr func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := s.Clean(inactive); err != nil && onErr != nil {
+				onErr(err)
 			}
 		}
 	}
-	return rows.Err()
 }
 
 func (s *Store) Delete(sess *sessions.Session) error {
 	id := []byte(sess.ID)
-	_, err := s.Exec("DELETE FROM " + SqlTableName + " WHERE id = $1", id)
+	_, err := s.Exec("DELETE FROM "+SqlTableName+" WHERE id = "+s.ph(1), id)
 	return err
 }
 
@@ -92,24 +122,29 @@ func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
 
 func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
 	sess := sessions.NewSession(s, name)
-	sess.ID = s.id(r)
+	sess.ID, sess.IsNew = s.id(r)
+	sess.Options = s.options()
 
 	RLock(sess)
 	defer RUnlock(sess)
 
 	rows, err := s.Query(`
 		SELECT key, value
-		FROM ` + SqlTableName + `
-		WHERE id = $1 AND name = $2
-	`, []byte(sess.ID), name)
+		FROM `+SqlTableName+`
+		WHERE id = `+s.ph(1)+` AND name = `+s.ph(2), []byte(sess.ID), name)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var k, v string
-		if err := rows.Scan(&k, &v); err != nil {
+		var k string
+		var raw []byte
+		if err := rows.Scan(&k, &raw); err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := s.Codec.Decode(raw, &v); err != nil {
 			return nil, err
 		}
 		sess.Values[k] = v
@@ -128,24 +163,56 @@ func (s *Store) Save(
 	Lock(sess)
 	defer Unlock(sess)
 
-	s.writeCookie(r, w, CookieIdName, sess.ID)
-
 	id := []byte(sess.ID)
-	sess.Values[SessionLastUpdated] = time.Now().UTC()
+
+	if sess.Options != nil && sess.Options.MaxAge < 0 {
+		if _, err := s.Exec("DELETE FROM "+SqlTableName+" WHERE id = "+s.ph(1), id); err != nil {
+			return err
+		}
+		s.writeCookie(r, w, CookieIdName, "", sess.Options)
+		return nil
+	}
+
+	s.writeCookie(r, w, CookieIdName, sess.ID, sess.Options)
+
+	keys := make([]string, 0, len(sess.Values))
+	for k := range sess.Values {
+		ks, ok := k.(string)
+		if !ok {
+			return fmt.Errorf("sqlsess: session value keys must be strings, got %T", k)
+		}
+		keys = append(keys, ks)
+	}
+
+	now := time.Now().UTC()
 	tx, err := s.Begin()
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec("DELETE FROM " + SqlTableName + " WHERE id = $1", id)
-	if err != nil {
+	// Drop any row for a key no longer in sess.Values; every key still
+	// present is written below via the dialect's upsert form, so it
+	// doesn't need to be deleted first.
+	delQuery := "DELETE FROM " + SqlTableName + " WHERE id = " + s.ph(1)
+	delArgs := []interface{}{id}
+	if len(keys) > 0 {
+		phs := make([]string, len(keys))
+		for i, k := range keys {
+			phs[i] = s.ph(i + 2)
+			delArgs = append(delArgs, k)
+		}
+		delQuery += " AND key NOT IN (" + strings.Join(phs, ", ") + ")"
+	}
+	if _, err := tx.Exec(delQuery, delArgs...); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	prep, err := tx.Prepare(`
 		INSERT INTO ` + SqlTableName + `
-			(id, name, key, value) VALUES ($1, $2, $3, $4)
+			(id, name, key, value, updated_at) VALUES (` +
+		s.ph(1) + `, ` + s.ph(2) + `, ` + s.ph(3) + `, ` + s.ph(4) + `, ` + s.ph(5) + `)
+		` + s.dialect.UpsertSQL() + `
 	`)
 	if err != nil {
 		tx.Rollback()
@@ -154,7 +221,12 @@ func (s *Store) Save(
 	defer prep.Close()
 
 	for k, v := range sess.Values {
-		if _, err := prep.Exec(id, sess.Name(), k, v); err != nil {
+		enc, err := s.Codec.Encode(v)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := prep.Exec(id, sess.Name(), k, enc, now); err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -163,41 +235,56 @@ func (s *Store) Save(
 }
 
 // id returns either the session id from a user's cookie or generates
-// a fresh one if the cookie is inaccessible or missing.
-func (s *Store) id(r *http.Request) string {
-	id := s.readCookie(r, CookieIdName)
+// a fresh one if the cookie is inaccessible or missing, along with whether
+// a fresh id was generated.
+func (s *Store) id(r *http.Request) (id string, isNew bool) {
+	id = s.readCookie(r, CookieIdName)
 	if len(id) == 0 {
-		id = string(securecookie.GenerateRandomKey(64))
+		return string(securecookie.GenerateRandomKey(64)), true
 	}
-	return id
+	return id, false
 }
 
-// SetKeys sets the hash and block keys used to read and write the session
-// cookie. A hash key is required and is used to to authenticate a cookie
-// value using HMAC. It's recommend to be 32 or 64 bytes.
-//
-// A block key is optional and is used to encrypt the cookie value. If it's
-// set to nil, then encryption will not be used. This package uses AES, so
-// the block key must have length 16, 24 or 32 bytes corresponding to
-// AES-128, AES-192 or AES-256. If the block key violates these constraints,
-// SetKeys will panic.
+// options returns a copy of s.Options for use as a new session's Options,
+// or gorilla's usual defaults if s.Options is unset.
+func (s *Store) options() *sessions.Options {
+	if s.Options == nil {
+		return &sessions.Options{Path: "/", HttpOnly: true}
+	}
+	opts := *s.Options
+	return &opts
+}
+
+// SetKeyPairs sets the (hash, block) key pairs used to read and write the
+// session cookie, in priority order. Each pair's hash key authenticates
+// the cookie with HMAC and should be 32 or 64 bytes; the block key
+// encrypts it with AES and must have length 16, 24 or 32, or be nil to
+// disable encryption for that pair.
 //
-// This method is exposed so that multiple instantiations of session stores
-// can share the same cookie. This particularly useful if you want to be able
-// to restart your web server without invalidating existing user sessions.
+// Encoding always uses the first pair. Decoding tries each pair in turn
+// until one succeeds, so an operator can rotate keys by prepending a new
+// pair, redeploying, and later removing the old pair once it's confident
+// every live cookie has been re-encoded with the new one — all without
+// forcing a logout.
 //
-// If this method is not called, then a fresh set of keys is created
+// If this method is not called, then a fresh key pair is created
 // automatically, but will invalidate all existing user sessions.
-func (s *Store) SetKeys(hash, block []byte) {
-	validLen := len(block) == 16 || len(block) == 24 || len(block) == 32
-	if block != nil && !validLen {
-		panic("invalid block key")
+func (s *Store) SetKeyPairs(pairs ...[]byte) {
+	for i := 1; i < len(pairs); i += 2 {
+		block := pairs[i]
+		validLen := len(block) == 16 || len(block) == 24 || len(block) == 32
+		if block != nil && !validLen {
+			panic("invalid block key")
+		}
 	}
-	s.hashKey, s.blockKey = hash, block
+	s.codecs = securecookie.CodecsFromPairs(pairs...)
 }
 
-func (s *Store) cookrw() *securecookie.SecureCookie {
-	return securecookie.New(s.hashKey, s.blockKey)
+// SetKeys sets a single (hash, block) key pair used to read and write the
+// session cookie. It's a thin wrapper around SetKeyPairs for callers that
+// don't need key rotation; see SetKeyPairs to use more than one pair.
+func (s *Store) SetKeys(hash, block []byte) {
+	s.SetKeyPairs(hash, block)
 }
 
 // Returns an empty string if the cookie doesn't exist or if there was
@@ -205,27 +292,27 @@ func (s *Store) cookrw() *securecookie.SecureCookie {
 func (s *Store) readCookie(r *http.Request, cname string) string {
 	if cook, err := r.Cookie(cname); err == nil {
 		var v string
-		if err = s.cookrw().Decode(cname, cook.Value, &v); err == nil {
+		if err := securecookie.DecodeMulti(cname, cook.Value, &v, s.codecs...); err == nil {
 			return v
 		}
 	}
 	return ""
 }
 
-// Writes the value to the named cookie with encryption.
+// Writes the value to the named cookie with encryption, using opts to
+// set the cookie's Path, Domain, MaxAge, Secure and SameSite attributes.
+// A nil opts falls back to gorilla's zero-value Options.
 func (s *Store) writeCookie(
 	r *http.Request,
 	w http.ResponseWriter,
 	cname, cvalue string,
+	opts *sessions.Options,
 ) {
-	if encoded, err := s.cookrw().Encode(cname, cvalue); err == nil {
-		cook := &http.Cookie{
-			Name:     cname,
-			Value:    encoded,
-			Path:     "/",
-			HttpOnly: true,
-		}
-		http.SetCookie(w, cook)
+	if opts == nil {
+		opts = &sessions.Options{}
+	}
+	if encoded, err := securecookie.EncodeMulti(cname, cvalue, s.codecs...); err == nil {
+		http.SetCookie(w, sessions.NewCookie(cname, encoded, opts))
 	}
 }
 
@@ -0,0 +1,131 @@
+package sqlsess
+
+import "fmt"
+
+// Dialect supplies the SQL that differs between database engines: the
+// CREATE TABLE statement (including the binary column type used for id
+// and value), the parameter placeholder style used in queries, and the
+// upsert clause Save appends to its INSERT.
+//
+// Implementations are provided for Postgres, MySQL and SQLite. Dialect is
+// exported so that other engines can be supported without modifying this
+// package.
+type Dialect interface {
+	// CreateTableSQL returns the DDL statements used to create the
+	// session table and its indexes if they don't already exist. Each
+	// statement is run as a separate db.Exec call, since not every
+	// driver accepts multiple statements in one call.
+	CreateTableSQL() []string
+
+	// Placeholder returns the parameter placeholder for the n'th
+	// (1-indexed) bound argument in a query, e.g. "$1" for Postgres or
+	// "?" for MySQL and SQLite.
+	Placeholder(n int) string
+
+	// UpsertSQL returns the clause appended after an
+	// "INSERT INTO sess_session (...) VALUES (...)" to turn it into an
+	// upsert keyed on the (id, name, key) primary key, so Save can write
+	// a row whether or not it already exists without a separate
+	// SELECT/DELETE round trip for that row.
+	UpsertSQL() string
+}
+
+// PostgresDialect targets Postgres (e.g. via lib/pq or pgx), using
+// $-numbered placeholders and a BYTEA binary column type.
+type PostgresDialect struct{}
+
+func (PostgresDialect) CreateTableSQL() []string {
+	return []string{
+		`
+		CREATE TABLE IF NOT EXISTS ` + SqlTableName + ` (
+			id BYTEA NOT NULL,
+			name VARCHAR (255) NOT NULL,
+			key TEXT NOT NULL,
+			value BYTEA NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (id, name, key)
+		)
+		`,
+		`
+		CREATE INDEX IF NOT EXISTS ` + SqlTableName + `_updated_at_idx
+			ON ` + SqlTableName + ` (updated_at)
+		`,
+	}
+}
+
+func (PostgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (PostgresDialect) UpsertSQL() string {
+	return `
+	ON CONFLICT (id, name, key) DO UPDATE SET
+		value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`
+}
+
+// MySQLDialect targets MySQL/MariaDB, using "?" placeholders and a
+// MEDIUMBLOB binary column type for value, since VARBINARY has a fixed max length
+// and MySQL silently truncates an over-long value in non-strict mode
+// instead of erroring.
+type MySQLDialect struct{}
+
+func (MySQLDialect) CreateTableSQL() []string {
+	return []string{
+		`
+		CREATE TABLE IF NOT EXISTS ` + SqlTableName + ` (
+			id VARBINARY(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			` + "`key`" + ` VARCHAR(255) NOT NULL,
+			value MEDIUMBLOB NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (id, name, ` + "`key`" + `),
+			INDEX ` + SqlTableName + `_updated_at_idx (updated_at)
+		)
+		`,
+	}
+}
+
+func (MySQLDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (MySQLDialect) UpsertSQL() string {
+	return `
+	ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = VALUES(updated_at)
+	`
+}
+
+// SQLiteDialect targets SQLite, using "?" placeholders and a BLOB binary
+// column type.
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) CreateTableSQL() []string {
+	return []string{
+		`
+		CREATE TABLE IF NOT EXISTS ` + SqlTableName + ` (
+			id BLOB NOT NULL,
+			name TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value BLOB NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (id, name, key)
+		)
+		`,
+		`
+		CREATE INDEX IF NOT EXISTS ` + SqlTableName + `_updated_at_idx
+			ON ` + SqlTableName + ` (updated_at)
+		`,
+	}
+}
+
+func (SQLiteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (SQLiteDialect) UpsertSQL() string {
+	return `
+	ON CONFLICT (id, name, key) DO UPDATE SET
+		value = excluded.value, updated_at = excluded.updated_at
+	`
+}
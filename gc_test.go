@@ -0,0 +1,91 @@
+package sqlsess
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// insertRow writes a row directly, bypassing Save, so its updated_at can
+// be backdated to simulate a stale session.
+func insertRow(t *testing.T, s *Store, id, key string, updatedAt time.Time) {
+	t.Helper()
+	enc, err := s.Codec.Encode("v")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := s.Exec(`
+		INSERT INTO `+SqlTableName+`
+			(id, name, key, value, updated_at) VALUES (?, ?, ?, ?, ?)
+	`, []byte(id), "test", key, enc, updatedAt); err != nil {
+		t.Fatalf("insert row: %v", err)
+	}
+}
+
+func rowCount(t *testing.T, s *Store) int {
+	t.Helper()
+	var n int
+	if err := s.QueryRow("SELECT COUNT(*) FROM " + SqlTableName).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+// TestCleanDeletesStaleRows verifies Clean deletes only rows whose
+// updated_at is older than the given inactive duration.
+func TestCleanDeletesStaleRows(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	now := time.Now().UTC()
+	insertRow(t, s, "stale", "k", now.Add(-2*time.Hour))
+	insertRow(t, s, "fresh", "k", now)
+
+	if err := s.Clean(time.Hour); err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+
+	if got, want := rowCount(t, s), 1; got != want {
+		t.Fatalf("rows after Clean = %d, want %d", got, want)
+	}
+
+	var id []byte
+	if err := s.QueryRow("SELECT id FROM " + SqlTableName).Scan(&id); err != nil {
+		t.Fatalf("select remaining id: %v", err)
+	}
+	if string(id) != "fresh" {
+		t.Errorf("remaining row id = %q, want %q", id, "fresh")
+	}
+}
+
+// TestStartGCDeletesOnTick verifies StartGC runs Clean on its ticker and
+// stops when ctx is cancelled.
+func TestStartGCDeletesOnTick(t *testing.T) {
+	s := newSQLiteStore(t)
+	insertRow(t, s, "stale", "k", time.Now().UTC().Add(-2*time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		s.StartGC(ctx, 10*time.Millisecond, time.Hour, func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	<-done
+	select {
+	case err := <-errs:
+		t.Fatalf("StartGC reported an error: %v", err)
+	default:
+	}
+
+	if got, want := rowCount(t, s), 0; got != want {
+		t.Fatalf("rows after StartGC = %d, want %d", got, want)
+	}
+}
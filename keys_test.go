@@ -0,0 +1,52 @@
+package sqlsess
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+)
+
+// TestSetKeyPairsRotation verifies the key-rotation promise documented on
+// SetKeyPairs: a cookie written with an old key pair still decodes after
+// a new pair is prepended, and newly written cookies use the new pair.
+func TestSetKeyPairsRotation(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	oldHash := securecookie.GenerateRandomKey(64)
+	oldBlock := securecookie.GenerateRandomKey(32)
+	s.SetKeyPairs(oldHash, oldBlock)
+
+	w := httptest.NewRecorder()
+	s.writeCookie(httptest.NewRequest("GET", "/", nil), w, CookieIdName, "old-value", nil)
+	oldCookie := w.Result().Cookies()[0]
+
+	newHash := securecookie.GenerateRandomKey(64)
+	newBlock := securecookie.GenerateRandomKey(32)
+	s.SetKeyPairs(newHash, newBlock, oldHash, oldBlock)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(oldCookie)
+	if got, want := s.readCookie(r, CookieIdName), "old-value"; got != want {
+		t.Errorf("readCookie(old cookie) after rotation = %q, want %q", got, want)
+	}
+
+	w2 := httptest.NewRecorder()
+	s.writeCookie(httptest.NewRequest("GET", "/", nil), w2, CookieIdName, "new-value", nil)
+	newCookie := w2.Result().Cookies()[0]
+
+	r2 := httptest.NewRequest("GET", "/", nil)
+	r2.AddCookie(newCookie)
+	if got, want := s.readCookie(r2, CookieIdName), "new-value"; got != want {
+		t.Errorf("readCookie(new cookie) = %q, want %q", got, want)
+	}
+
+	// Once the old pair is dropped entirely, the old cookie must stop
+	// decoding.
+	s.SetKeyPairs(newHash, newBlock)
+	r3 := httptest.NewRequest("GET", "/", nil)
+	r3.AddCookie(oldCookie)
+	if got := s.readCookie(r3, CookieIdName); got != "" {
+		t.Errorf("readCookie(old cookie) after dropping old pair = %q, want \"\"", got)
+	}
+}
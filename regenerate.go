@@ -0,0 +1,53 @@
+package sqlsess
+
+import (
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"github.com/BurntSushi/locker"
+)
+
+// RegenerateID replaces sess's id with a freshly generated one, updates the
+// corresponding row(s) in sess_session to the new id, and rewrites the
+// session cookie to match. Callers should call this immediately after a
+// successful login, before writing any new session state, to defend
+// against session fixation attacks.
+//
+// The row update and the in-memory ID swap both happen under the same
+// lock used by Save, so a concurrent Save for this session will either
+// see the old id (and run before the rename) or the new one (and run
+// after), never a mix of the two.
+func (s *Store) RegenerateID(
+	r *http.Request,
+	w http.ResponseWriter,
+	sess *sessions.Session,
+) error {
+	newID := string(securecookie.GenerateRandomKey(64))
+
+	oldIDStr := sess.ID
+	locker.Lock(oldIDStr)
+	defer locker.Unlock(oldIDStr)
+
+	oldID := []byte(oldIDStr)
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE "+SqlTableName+" SET id = "+s.ph(1)+" WHERE id = "+s.ph(2),
+		[]byte(newID), oldID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sess.ID = newID
+	s.writeCookie(r, w, CookieIdName, sess.ID, sess.Options)
+	return nil
+}
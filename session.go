@@ -0,0 +1,96 @@
+package sqlsess
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/sessions"
+)
+
+// Session wraps a *sessions.Session with a sync.RWMutex, so that
+// concurrent in-flight requests for the same user (e.g. an SSE stream and
+// a POST handler) can safely read and write Values without racing each
+// other. The locker.Lock used elsewhere in this package only guards DB
+// access; it says nothing about two goroutines sharing one *sessions.
+// Session in memory.
+//
+// Use Store.GetSafe / Store.NewSafe to obtain one, and Store.SaveSafe to
+// persist it.
+type Session struct {
+	*sessions.Session
+	mu sync.RWMutex
+}
+
+// GetSafe is like Get, but returns the session wrapped in a Session for
+// safe concurrent access from multiple handlers.
+func (s *Store) GetSafe(r *http.Request, name string) (*Session, error) {
+	sess, err := s.Get(r, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Session: sess}, nil
+}
+
+// NewSafe is like New, but returns the session wrapped in a Session for
+// safe concurrent access from multiple handlers.
+func (s *Store) NewSafe(r *http.Request, name string) (*Session, error) {
+	sess, err := s.New(r, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Session: sess}, nil
+}
+
+// SaveSafe persists ss, snapshotting its Values under a write lock so
+// that a concurrent Set/Delete can't race with what actually gets
+// written to the database.
+func (s *Store) SaveSafe(r *http.Request, w http.ResponseWriter, ss *Session) error {
+	ss.mu.Lock()
+	snapshot := *ss.Session
+	snapshot.Values = make(map[interface{}]interface{}, len(ss.Values))
+	for k, v := range ss.Values {
+		snapshot.Values[k] = v
+	}
+	ss.mu.Unlock()
+
+	if err := s.Save(r, w, &snapshot); err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	ss.Session.ID = snapshot.ID
+	ss.Session.Options = snapshot.Options
+	ss.mu.Unlock()
+	return nil
+}
+
+// Set stores v under k, safe for concurrent use.
+func (ss *Session) Set(k, v interface{}) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.Values[k] = v
+}
+
+// Get returns the value stored under k, and whether it was present, safe
+// for concurrent use.
+func (ss *Session) Get(k interface{}) (interface{}, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	v, ok := ss.Values[k]
+	return v, ok
+}
+
+// Delete removes k, safe for concurrent use.
+func (ss *Session) Delete(k interface{}) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.Values, k)
+}
+
+// Fresh reports whether this session was just created, rather than
+// loaded from an existing cookie.
+func (ss *Session) Fresh() bool {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.IsNew
+}
@@ -0,0 +1,53 @@
+package sqlsess
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+// TestSessionConcurrentAccess exercises Set/Get/Delete from many
+// goroutines at once. Run with `go test -race` to confirm the Session
+// wrapper's RWMutex actually closes the race that a bare
+// *sessions.Session leaves open when shared between in-flight requests.
+func TestSessionConcurrentAccess(t *testing.T) {
+	ss := &Session{Session: sessions.NewSession(nil, "test")}
+
+	var wg sync.WaitGroup
+	const goroutines = 16
+	const iterations = 100
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				ss.Set("k", i*iterations+j)
+				ss.Get("k")
+				ss.Delete("k")
+				ss.Fresh()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSessionSetGetDelete(t *testing.T) {
+	ss := &Session{Session: sessions.NewSession(nil, "test")}
+
+	if _, ok := ss.Get("k"); ok {
+		t.Fatal("Get on empty session returned ok=true")
+	}
+
+	ss.Set("k", "v")
+	v, ok := ss.Get("k")
+	if !ok || v != "v" {
+		t.Fatalf("Get after Set = %v, %v; want v, true", v, ok)
+	}
+
+	ss.Delete("k")
+	if _, ok := ss.Get("k"); ok {
+		t.Fatal("Get after Delete returned ok=true")
+	}
+}
@@ -0,0 +1,98 @@
+package sqlsess
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteStore opens a fresh in-memory SQLite-backed Store, for tests
+// that need a real driver rather than sqltest_test.go's fake one.
+func newSQLiteStore(t *testing.T) *Store {
+	t.Helper()
+	// Each test gets its own named in-memory database (shared across
+	// the connection pool via cache=shared) so tests can't see each
+	// other's rows.
+	dsn := "file:" + t.Name() + "?mode=memory&cache=shared"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s, err := Open(db, SQLiteDialect{})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return s
+}
+
+// TestStoreSaveNewNonStringValue is a regression test for the bug fixed
+// in 045cd01: GobCodec.Decode used to fail with "gob: local interface
+// type *interface {} can only be decoded from remote interface type"
+// for any non-string value, because Store.New decodes each row into a
+// bare *interface{}.
+func TestStoreSaveNewNonStringValue(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	saveReq := httptest.NewRequest("GET", "/", nil)
+	saveRec := httptest.NewRecorder()
+	sess, err := s.New(saveReq, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sess.Values["userID"] = 42
+	sess.Values["name"] = "alice"
+	if err := s.Save(saveReq, saveRec, sess); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Replay the cookie the first Save set to load the same session.
+	loadReq := httptest.NewRequest("GET", "/", nil)
+	for _, c := range saveRec.Result().Cookies() {
+		loadReq.AddCookie(c)
+	}
+
+	loaded, err := s.New(loadReq, "test")
+	if err != nil {
+		t.Fatalf("New (reload): %v", err)
+	}
+	if got, want := loaded.Values["userID"], 42; got != want {
+		t.Errorf("Values[userID] = %#v (%T), want %#v (%T)", got, got, want, want)
+	}
+	if got, want := loaded.Values["name"], "alice"; got != want {
+		t.Errorf("Values[name] = %#v (%T), want %#v (%T)", got, got, want, want)
+	}
+}
+
+// TestStoreSaveNewJSONCodec is the same round-trip using JSONCodec, to
+// make sure Store isn't implicitly relying on gob-specific behavior.
+func TestStoreSaveNewJSONCodec(t *testing.T) {
+	s := newSQLiteStore(t)
+	s.Codec = JSONCodec{}
+
+	saveReq := httptest.NewRequest("GET", "/", nil)
+	saveRec := httptest.NewRecorder()
+	sess, err := s.New(saveReq, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sess.Values["count"] = float64(7)
+	if err := s.Save(saveReq, saveRec, sess); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loadReq := httptest.NewRequest("GET", "/", nil)
+	for _, c := range saveRec.Result().Cookies() {
+		loadReq.AddCookie(c)
+	}
+	loaded, err := s.New(loadReq, "test")
+	if err != nil {
+		t.Fatalf("New (reload): %v", err)
+	}
+	if got, want := loaded.Values["count"], float64(7); got != want {
+		t.Errorf("Values[count] = %#v, want %#v", got, want)
+	}
+}
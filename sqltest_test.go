@@ -0,0 +1,68 @@
+package sqlsess
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// fakeDriver is a minimal database/sql/driver implementation used so the
+// locking tests can exercise Store against a real *sql.DB without
+// depending on an actual SQL engine. It accepts every statement and
+// returns empty results; the tests below only care about locking
+// behavior, not persisted data.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (*fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{}, nil }
+func (*fakeConn) Close() error                              { return nil }
+func (*fakeConn) Begin() (driver.Tx, error)                 { return &fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (*fakeTx) Commit() error   { return nil }
+func (*fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct{}
+
+func (*fakeStmt) Close() error  { return nil }
+func (*fakeStmt) NumInput() int { return -1 }
+func (*fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (*fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (*fakeRows) Columns() []string { return []string{"id", "value"} }
+func (*fakeRows) Close() error      { return nil }
+func (*fakeRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+var registerFakeDriverOnce sync.Once
+
+// newFakeStore opens a Store against fakeDriver, so tests can exercise
+// locking and control flow without a real database.
+func newFakeStore() *Store {
+	registerFakeDriverOnce.Do(func() {
+		sql.Register("sqlsessfake", fakeDriver{})
+	})
+	db, err := sql.Open("sqlsessfake", "")
+	if err != nil {
+		panic(err)
+	}
+	s, err := Open(db, PostgresDialect{})
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package sqlsess
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+// TestSaveMaxAgeNegativeDeletes is a regression test for the MaxAge<0
+// branch of Save: it must delete the session's row and write an expired
+// cookie instead of persisting the values.
+func TestSaveMaxAgeNegativeDeletes(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	sess, err := s.New(req, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sess.Values["x"] = 1
+	if err := s.Save(req, rec, sess); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var rows int
+	if err := s.QueryRow(
+		"SELECT COUNT(*) FROM "+SqlTableName+" WHERE id = ?", []byte(sess.ID),
+	).Scan(&rows); err != nil {
+		t.Fatalf("count after save: %v", err)
+	}
+	if rows == 0 {
+		t.Fatalf("rows after save = 0, want > 0")
+	}
+
+	delReq := httptest.NewRequest("GET", "/", nil)
+	delRec := httptest.NewRecorder()
+	sess.Options = &sessions.Options{MaxAge: -1}
+	if err := s.Save(delReq, delRec, sess); err != nil {
+		t.Fatalf("Save (MaxAge<0): %v", err)
+	}
+
+	if err := s.QueryRow(
+		"SELECT COUNT(*) FROM "+SqlTableName+" WHERE id = ?", []byte(sess.ID),
+	).Scan(&rows); err != nil {
+		t.Fatalf("count after delete: %v", err)
+	}
+	if rows != 0 {
+		t.Fatalf("rows after MaxAge<0 save = %d, want 0", rows)
+	}
+
+	cookies := delRec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	if cookies[0].MaxAge >= 0 {
+		t.Errorf("cookie MaxAge = %d, want < 0 (expired)", cookies[0].MaxAge)
+	}
+}
+
+// TestStoreOptionsSeedsSession verifies Store.Options is used to seed
+// every new session's Options, as documented on the Options field.
+func TestStoreOptionsSeedsSession(t *testing.T) {
+	s := newSQLiteStore(t)
+	s.Options = &sessions.Options{Path: "/app", MaxAge: 3600, HttpOnly: true}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	sess, err := s.New(req, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if got, want := sess.Options.Path, "/app"; got != want {
+		t.Errorf("Options.Path = %q, want %q", got, want)
+	}
+	if got, want := sess.Options.MaxAge, 3600; got != want {
+		t.Errorf("Options.MaxAge = %d, want %d", got, want)
+	}
+	if !sess.Options.HttpOnly {
+		t.Errorf("Options.HttpOnly = false, want true")
+	}
+
+	// New must hand back a copy, not a shared pointer, so a caller
+	// mutating one session's Options can't affect the store's default
+	// or other sessions.
+	sess.Options.Path = "/other"
+	if s.Options.Path != "/app" {
+		t.Errorf("mutating session Options changed Store.Options.Path to %q", s.Options.Path)
+	}
+}